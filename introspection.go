@@ -0,0 +1,103 @@
+package simpleserver
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+)
+
+// introspectionConfig controls which probe handlers WithIntrospection
+// registers on the introspection listener's mux.
+type introspectionConfig struct {
+	healthz   bool
+	readyz    bool
+	metrics   bool
+	pprof     bool
+	readyFunc func() bool
+}
+
+// IntrospectionOpt configures the handlers registered by WithIntrospection.
+type IntrospectionOpt func(*introspectionConfig)
+
+// WithHealthz toggles the /healthz liveness handler, which is registered by
+// default.
+func WithHealthz(enabled bool) IntrospectionOpt {
+	return func(c *introspectionConfig) {
+		c.healthz = enabled
+	}
+}
+
+// WithReadyz toggles the /readyz readiness handler, which is registered by
+// default. fn, if non-nil, is consulted alongside Draining() to decide
+// whether the server reports ready.
+func WithReadyz(enabled bool, fn func() bool) IntrospectionOpt {
+	return func(c *introspectionConfig) {
+		c.readyz = enabled
+		c.readyFunc = fn
+	}
+}
+
+// WithMetrics toggles the /metrics expvar handler, which is registered by
+// default.
+func WithMetrics(enabled bool) IntrospectionOpt {
+	return func(c *introspectionConfig) {
+		c.metrics = enabled
+	}
+}
+
+// WithPprof toggles the /debug/pprof/* handlers. They are off by default
+// since they can leak information about the running process.
+func WithPprof(enabled bool) IntrospectionOpt {
+	return func(c *introspectionConfig) {
+		c.pprof = enabled
+	}
+}
+
+// introspectionMux builds the handler for the introspection listener out of
+// the configured probe handlers.
+func (s *Server) introspectionMux(opts []IntrospectionOpt) http.Handler {
+	cfg := introspectionConfig{
+		healthz: true,
+		readyz:  true,
+		metrics: true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	mux := http.NewServeMux()
+
+	if cfg.healthz {
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	if cfg.readyz {
+		mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+			ready := !s.Draining()
+			if cfg.readyFunc != nil {
+				ready = ready && cfg.readyFunc()
+			}
+			if !ready {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+
+	if cfg.metrics {
+		mux.Handle("/metrics", expvar.Handler())
+	}
+
+	if cfg.pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return mux
+}
@@ -3,13 +3,20 @@ package simpleserver
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/cors"
+	"golang.org/x/sync/errgroup"
 )
 
 // Server is the struct for configuring a server
@@ -23,7 +30,71 @@ type Server struct {
 	KeyFile      string
 	// ShutdownTimeout timeout for server to gracefully shutdown
 	ShutdownTimeout time.Duration
-	srv             http.Server
+	// ReadHeaderTimeout bounds the time spent reading request headers
+	ReadHeaderTimeout time.Duration
+	// IdleTimeout bounds how long to keep idle keep-alive connections open
+	IdleTimeout time.Duration
+	// MaxHeaderBytes caps the size of request headers the server will read
+	MaxHeaderBytes int
+	// TLSConfig, when set, is used verbatim for TLS connections instead of Go's
+	// default tls.Config. Set it directly with WithTLSConfig, or start from the
+	// hardened baseline installed by WithHardenedDefaults.
+	TLSConfig *tls.Config
+	// GetCertificate, when set, is wired into TLSConfig.GetCertificate. It lets
+	// callers rotate certificates (and staple OCSP responses onto them) without
+	// restarting the server.
+	GetCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	// SessionTicketRotation, when non-zero, rotates the TLS session ticket keys
+	// on this interval so a compromised key has a bounded blast radius. Only
+	// takes effect when TLS is in use.
+	SessionTicketRotation time.Duration
+	// ShutdownDelay, when non-zero, is slept at the start of Shutdown before
+	// the listener is closed, giving service discovery time to stop routing
+	// new connections here before they start being refused.
+	ShutdownDelay time.Duration
+	draining      atomic.Bool
+	readiness     *atomic.Bool
+	// RedirectHTTPPort, when non-zero, starts a plain-HTTP listener on this
+	// port alongside the main listener that redirects every request to the
+	// same host on Port.
+	RedirectHTTPPort int
+	// IntrospectionAddr, when set, starts a separate listener on this address
+	// serving operational probe handlers (/healthz, /readyz, /metrics,
+	// /debug/pprof) configured via the IntrospectionOpts passed to
+	// WithIntrospection.
+	IntrospectionAddr string
+	introspectionOpts []IntrospectionOpt
+	// connStateHook, when set, is called with every ConnState transition on
+	// the main listener, chained after the tracking that backs ActiveConns.
+	connStateHook func(net.Conn, http.ConnState)
+	// shutdownInitiatedCallback, when set, is called once the BeforeShutdown
+	// hooks have let Shutdown proceed (it is not called if one of them vetoes
+	// it), before the drain delay and before the listener closes, so
+	// applications can warn long-lived clients (e.g. tell websocket clients
+	// to reconnect) before the forced close below kicks in.
+	shutdownInitiatedCallback func()
+	// activeConns tracks every net.Conn the main listener has seen, mapping
+	// each to its most recent http.ConnState. Entries are removed on StateClosed, but
+	// Go stops reporting ConnState for hijacked connections (websockets/SSE)
+	// once they're hijacked, so callers that hijack must call DeregisterConn
+	// themselves when they're done with the connection; otherwise it stays
+	// tracked until Shutdown force closes it.
+	activeConns sync.Map
+	// beforeShutdownHooks run in registration order at the start of Shutdown.
+	// Any hook returning false aborts the shutdown before the listener closes.
+	beforeShutdownHooks []func() bool
+	// afterShutdownHooks run in registration order once Shutdown has finished
+	// (successfully or not), each receiving the error Shutdown is about to
+	// return.
+	afterShutdownHooks []func(error)
+	// ready is closed once Run has finished constructing every *http.Server
+	// it's about to start, so Shutdown can't race it and see srv/redirectSrv/
+	// introspectionSrv still nil.
+	ready            chan struct{}
+	readyOnce        sync.Once
+	srv              *http.Server
+	redirectSrv      *http.Server
+	introspectionSrv *http.Server
 }
 
 type serverOpt func(s *Server)
@@ -85,6 +156,151 @@ func WithCorsHandler(h http.Handler, c cors.Options) serverOpt {
 	}
 }
 
+// WithTLSConfig overrides the tls.Config used when the server is serving
+// TLS, taking precedence over WithHardenedDefaults if both are supplied.
+func WithTLSConfig(c *tls.Config) serverOpt {
+	return func(s *Server) {
+		s.TLSConfig = c
+	}
+}
+
+// WithHardenedDefaults applies an opinionated, Cloudflare-blog-inspired TLS
+// and timeout profile: TLS1.2 minimum with a curated AEAD+ECDHE cipher
+// suite list, X25519/P-256/P-384 curves only, and tightened
+// ReadHeaderTimeout/IdleTimeout/MaxHeaderBytes. It is meant as a single
+// opt-in for production deployments; it does not override a TLSConfig set
+// separately via WithTLSConfig.
+func WithHardenedDefaults() serverOpt {
+	return func(s *Server) {
+		if s.TLSConfig == nil {
+			s.TLSConfig = hardenedTLSConfig()
+		}
+		s.ReadHeaderTimeout = 5 * time.Second
+		s.IdleTimeout = 30 * time.Second
+		s.MaxHeaderBytes = 1 << 16 // 64KB
+	}
+}
+
+// hardenedTLSConfig returns the baseline tls.Config installed by
+// WithHardenedDefaults.
+func hardenedTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+		CurvePreferences: []tls.CurveID{
+			tls.X25519,
+			tls.CurveP256,
+			tls.CurveP384,
+		},
+	}
+}
+
+// WithGetCertificate wires fn into the server's TLSConfig.GetCertificate,
+// letting callers rotate certificates (and attach fresh OCSP staples) at
+// handshake time instead of restarting the server.
+func WithGetCertificate(fn func(*tls.ClientHelloInfo) (*tls.Certificate, error)) serverOpt {
+	return func(s *Server) {
+		s.GetCertificate = fn
+	}
+}
+
+// WithSessionTicketRotation rotates the TLS session ticket keys every d so a
+// leaked key only exposes traffic within that window. Only takes effect when
+// TLS is in use.
+func WithSessionTicketRotation(d time.Duration) serverOpt {
+	return func(s *Server) {
+		s.SessionTicketRotation = d
+	}
+}
+
+// WithShutdownDelay configures Server to sleep for d at the start of
+// Shutdown, before the listener is closed. This is meant for K8s/load
+// balancer environments where endpoint removal propagates asynchronously:
+// the pod keeps serving traffic for a few seconds after SIGTERM so callers
+// notified via service discovery have time to stop routing to it.
+func WithShutdownDelay(d time.Duration) serverOpt {
+	return func(s *Server) {
+		s.ShutdownDelay = d
+	}
+}
+
+// WithReadinessFlag registers flag to be flipped to false as soon as
+// Shutdown begins draining, before the ShutdownDelay sleep. Callers can wire
+// this into their readiness probe handler.
+func WithReadinessFlag(flag *atomic.Bool) serverOpt {
+	return func(s *Server) {
+		s.readiness = flag
+	}
+}
+
+// WithRedirectHTTP starts a plain-HTTP listener on port alongside the main
+// listener that redirects every request to the same host on Port. It is
+// meant to pair with TLS on the main listener.
+func WithRedirectHTTP(port int) serverOpt {
+	return func(s *Server) {
+		s.RedirectHTTPPort = port
+	}
+}
+
+// WithIntrospection starts a separate listener on addr serving operational
+// probe handlers: /healthz, /readyz, /metrics, and (opt-in) /debug/pprof.
+// Keeping these off the main listener means they aren't reachable from
+// outside the cluster and aren't subject to the same TLS/auth requirements
+// as application traffic.
+func WithIntrospection(addr string, opts ...IntrospectionOpt) serverOpt {
+	return func(s *Server) {
+		s.IntrospectionAddr = addr
+		s.introspectionOpts = opts
+	}
+}
+
+// WithShutdownInitiatedCallback registers fn to run once the BeforeShutdown
+// hooks have let Shutdown proceed, before the drain delay and before the
+// listener closes; it does not run if a BeforeShutdown hook vetoes. It is
+// meant for broadcasting a "please reconnect" message to long-lived clients
+// (websockets/SSE) before the forced close that follows a Shutdown timeout
+// would otherwise drop them without warning.
+func WithShutdownInitiatedCallback(fn func()) serverOpt {
+	return func(s *Server) {
+		s.shutdownInitiatedCallback = fn
+	}
+}
+
+// WithConnStateHook chains fn onto the main listener's http.ConnState
+// callback, alongside the tracking that backs ActiveConns.
+func WithConnStateHook(fn func(net.Conn, http.ConnState)) serverOpt {
+	return func(s *Server) {
+		s.connStateHook = fn
+	}
+}
+
+// WithBeforeShutdown registers fn to run at the start of Shutdown, before
+// the listener closes. Multiple hooks compose in registration order; fn
+// returning false aborts the shutdown before anything else runs, which is
+// useful for leader-election handoff or a final cache flush that needs to
+// veto shutdown until it's done.
+func WithBeforeShutdown(fn func() bool) serverOpt {
+	return func(s *Server) {
+		s.beforeShutdownHooks = append(s.beforeShutdownHooks, fn)
+	}
+}
+
+// WithAfterShutdown registers fn to run once Shutdown has finished, with the
+// error Shutdown is about to return. Multiple hooks compose in registration
+// order. This is useful for closing databases and flushing telemetry.
+func WithAfterShutdown(fn func(error)) serverOpt {
+	return func(s *Server) {
+		s.afterShutdownHooks = append(s.afterShutdownHooks, fn)
+	}
+}
+
 // NewServer returns a server configured with sensible defaults. These defaults can be overriden with zero or more serverOpts
 func NewServer(opts ...serverOpt) *Server {
 
@@ -94,6 +310,8 @@ func NewServer(opts ...serverOpt) *Server {
 		ShutdownTimeout: 15 * time.Second,
 		ReadTimeout:     15 * time.Second,
 		WriteTimeout:    15 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ready:           make(chan struct{}),
 	}
 
 	for _, opt := range opts {
@@ -103,12 +321,160 @@ func NewServer(opts ...serverOpt) *Server {
 	return s
 }
 
-// Shutdown allows the stopping a running server. It will attempt to gracefully shutdown with the configured ShutdownTimeout. Calling Shutdown before Run() will panic
+// errShutdownAborted is returned by Shutdown when a BeforeShutdown hook
+// vetoes the shutdown.
+var errShutdownAborted = errors.New("simpleserver: shutdown aborted by BeforeShutdown hook")
+
+// Shutdown gracefully stops every listener started by Run (the main
+// listener, and the redirect/introspection listeners if configured) in
+// parallel, within the configured ShutdownTimeout, and aggregates their
+// errors. If the timeout expires, or any connections - including hijacked
+// websocket/SSE connections, which graceful shutdown does not wait out -
+// are still open once the graceful pass finishes, it force-closes
+// everything instead of leaking them. BeforeShutdown hooks run first and
+// can veto the shutdown; AfterShutdown hooks run last, regardless of
+// outcome, with the error Shutdown returns. Calling Shutdown before Run()
+// has been called blocks until Run() runs; if Run() is never called, that
+// block is permanent, so callers should always start Run() first.
 func (s *Server) Shutdown() error {
+	for _, hook := range s.beforeShutdownHooks {
+		if !hook() {
+			err := errShutdownAborted
+			for _, after := range s.afterShutdownHooks {
+				after(err)
+			}
+			return err
+		}
+	}
+
+	err := s.shutdown()
+
+	for _, after := range s.afterShutdownHooks {
+		after(err)
+	}
+
+	return err
+}
+
+// shutdown performs the actual graceful-then-forced shutdown, after the
+// BeforeShutdown hooks have cleared it.
+func (s *Server) shutdown() error {
+	// Wait for Run to finish constructing srv/redirectSrv/introspectionSrv
+	// before touching them, so a Shutdown triggered concurrently with (or
+	// immediately after) Run starting can't see them still nil and return
+	// having shut nothing down.
+	<-s.ready
+
+	if s.shutdownInitiatedCallback != nil {
+		s.shutdownInitiatedCallback()
+	}
+
+	if s.ShutdownDelay > 0 {
+		s.draining.Store(true)
+		if s.readiness != nil {
+			s.readiness.Store(false)
+		}
+		time.Sleep(s.ShutdownDelay)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), s.ShutdownTimeout)
 	defer cancel()
 
-	return s.srv.Shutdown(ctx)
+	servers := []*http.Server{s.srv, s.redirectSrv, s.introspectionSrv}
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, srv := range servers {
+		if srv == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, srv *http.Server) {
+			defer wg.Done()
+			errs[i] = srv.Shutdown(ctx)
+		}(i, srv)
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if errors.Is(err, context.DeadlineExceeded) {
+		s.forceClose()
+		return nil
+	}
+
+	// http.Server.Shutdown stops tracking a connection the instant it's
+	// hijacked, so it reports quiescent success even while hijacked
+	// websocket/SSE connections are still open. Force-close whatever is left
+	// in activeConns rather than leaking those connections whenever nothing
+	// else was in flight to trip the timeout above.
+	if err == nil && s.ActiveConns() > 0 {
+		s.forceClose()
+	}
+
+	return err
+}
+
+// Draining reports whether Shutdown has begun draining: it becomes true when
+// Shutdown starts sleeping out a non-zero ShutdownDelay and stays true from
+// then on. With a zero ShutdownDelay it is never true.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// ActiveConns returns the number of connections the main listener currently
+// considers open, including hijacked connections (websockets/SSE) that have
+// no further ConnState transitions to report.
+func (s *Server) ActiveConns() int {
+	n := 0
+	s.activeConns.Range(func(_, _ interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// DeregisterConn removes c from the set of connections ActiveConns counts.
+// Go stops reporting ConnState transitions for a connection once it has
+// been hijacked (http.StateHijacked is terminal), so a handler that hijacks
+// a connection - for a websocket or SSE stream - must call DeregisterConn
+// itself once it is done with c, or c will stay counted as active (and
+// retained in memory) until Shutdown force closes it.
+func (s *Server) DeregisterConn(c net.Conn) {
+	s.activeConns.Delete(c)
+}
+
+// connState is installed as the main listener's http.ConnState callback. It
+// maintains activeConns and chains through to connStateHook, if set.
+func (s *Server) connState(c net.Conn, cs http.ConnState) {
+	if cs == http.StateClosed {
+		s.activeConns.Delete(c)
+	} else {
+		s.activeConns.Store(c, cs)
+	}
+
+	if s.connStateHook != nil {
+		s.connStateHook(c, cs)
+	}
+}
+
+// forceClose is the Shutdown fallback once the ShutdownTimeout has expired,
+// or once the graceful pass has finished with connections still tracked:
+// it closes every listener immediately via srv.Close(), then force-closes
+// any connections still tracked in activeConns - chiefly hijacked
+// websocket/SSE connections that srv.Close() does not know about.
+func (s *Server) forceClose() {
+	for _, srv := range []*http.Server{s.srv, s.redirectSrv, s.introspectionSrv} {
+		if srv != nil {
+			srv.Close()
+		}
+	}
+
+	s.activeConns.Range(func(key, _ interface{}) bool {
+		if conn, ok := key.(net.Conn); ok {
+			conn.Close()
+		}
+		s.activeConns.Delete(key)
+		return true
+	})
 }
 
 // WithSigShutdown will shutdown the running server when the provided sig happens. This call is blocking, so it is likely you will want to run it in a go routine in concert with server.Run()
@@ -130,20 +496,158 @@ func (s *Server) WithContextShutdown(ctx context.Context) error {
 	return s.Shutdown()
 }
 
-// Run will start the http server and block until it has been Shutdown.
+// WithSignalShutdown will shutdown the running server when any of the provided
+// signals are received, built on signal.NotifyContext. Like WithSigShutdown,
+// this call is blocking, so it is likely you will want to run it in a go
+// routine in concert with server.Run().
+func (s *Server) WithSignalShutdown(sigs ...os.Signal) error {
+	ctx, stop := signal.NotifyContext(context.Background(), sigs...)
+	defer stop()
+
+	<-ctx.Done()
+
+	return s.Shutdown()
+}
+
+// ListenAndServeWithShutdown runs the server and blocks until ctx is
+// cancelled, at which point it calls Shutdown and waits for Run to return.
+// It collapses the common goroutine dance of running Run and a shutdown
+// trigger side by side and plumbing their errors together into a single
+// blocking call.
+func (s *Server) ListenAndServeWithShutdown(ctx context.Context) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.Run()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	if err := s.Shutdown(); err != nil {
+		return err
+	}
+
+	return <-errCh
+}
+
+// Run starts the main listener and, if configured, the redirect and
+// introspection listeners alongside it, coordinating all of them through an
+// errgroup: if any listener fails, Run returns that error and the others are
+// expected to be torn down via Shutdown. Run blocks until every listener has
+// stopped.
 func (s *Server) Run() error {
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", s.Addr, s.Port),
-		WriteTimeout: s.WriteTimeout,
-		ReadTimeout:  s.ReadTimeout,
-		IdleTimeout:  time.Second * 60,
-		Handler:      s.Handler,
+		Addr:              fmt.Sprintf("%s:%d", s.Addr, s.Port),
+		WriteTimeout:      s.WriteTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		IdleTimeout:       s.IdleTimeout,
+		MaxHeaderBytes:    s.MaxHeaderBytes,
+		Handler:           s.Handler,
+		TLSConfig:         s.TLSConfig,
+		ConnState:         s.connState,
+	}
+	s.srv = srv
+
+	var redirectSrv *http.Server
+	if s.RedirectHTTPPort != 0 {
+		redirectSrv = &http.Server{
+			Addr:    fmt.Sprintf("%s:%d", s.Addr, s.RedirectHTTPPort),
+			Handler: redirectToPort(s.Port),
+		}
+		s.redirectSrv = redirectSrv
+	}
+
+	var introspectionSrv *http.Server
+	if s.IntrospectionAddr != "" {
+		introspectionSrv = &http.Server{
+			Addr:    s.IntrospectionAddr,
+			Handler: s.introspectionMux(s.introspectionOpts),
+		}
+		s.introspectionSrv = introspectionSrv
+	}
+
+	// Every *http.Server this Run is about to start is now registered on s,
+	// so it's safe for a concurrent Shutdown to stop waiting and read them.
+	s.readyOnce.Do(func() { close(s.ready) })
+
+	var g errgroup.Group
+
+	g.Go(func() error {
+		if s.CertFile != "" && s.KeyFile != "" {
+			if s.GetCertificate != nil {
+				if srv.TLSConfig == nil {
+					srv.TLSConfig = &tls.Config{}
+				}
+				srv.TLSConfig.GetCertificate = s.GetCertificate
+			}
+
+			if s.SessionTicketRotation > 0 {
+				if srv.TLSConfig == nil {
+					srv.TLSConfig = &tls.Config{}
+				}
+				go rotateSessionTicketKeys(srv.TLSConfig, s.SessionTicketRotation)
+			}
+
+			return ignoreServerClosed(srv.ListenAndServeTLS(s.CertFile, s.KeyFile))
+		}
+
+		return ignoreServerClosed(srv.ListenAndServe())
+	})
+
+	if redirectSrv != nil {
+		g.Go(func() error {
+			return ignoreServerClosed(redirectSrv.ListenAndServe())
+		})
 	}
 
-	if s.CertFile != "" && s.KeyFile != "" {
-		return srv.ListenAndServeTLS(s.CertFile, s.KeyFile)
+	if introspectionSrv != nil {
+		g.Go(func() error {
+			return ignoreServerClosed(introspectionSrv.ListenAndServe())
+		})
 	}
 
-	return srv.ListenAndServe()
+	return g.Wait()
+}
+
+// ignoreServerClosed maps the expected "listener closed by Shutdown" error
+// to nil so it doesn't fail the errgroup the listener is running under.
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// redirectToPort returns a handler that redirects every request to the same
+// host on httpsPort, over https.
+func redirectToPort(httpsPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+
+		target := fmt.Sprintf("https://%s:%d%s", host, httpsPort, r.URL.RequestURI())
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
+
+// rotateSessionTicketKeys installs a fresh, random TLS session ticket key on
+// c every interval, for as long as the server process runs.
+func rotateSessionTicketKeys(c *tls.Config, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var key [32]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			continue
+		}
+		c.SetSessionTicketKeys([][32]byte{key})
+	}
 }
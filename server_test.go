@@ -0,0 +1,346 @@
+package simpleserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// freePort asks the OS for an ephemeral port and immediately releases it so
+// a test Server can bind it.
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("freePort: %v", err)
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// waitForListener blocks until addr accepts a connection or the deadline
+// passes, so tests don't race Run's goroutine standing up the listener.
+func waitForListener(t *testing.T, addr string) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("listener at %s did not come up in time", addr)
+}
+
+func TestShutdownDrainDelayAndReadiness(t *testing.T) {
+	port := freePort(t)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	var ready atomic.Bool
+	ready.Store(true)
+
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(port),
+		WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		WithShutdownDelay(50*time.Millisecond),
+		WithReadinessFlag(&ready),
+		WithShutdownTimeout(time.Second),
+	)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+	waitForListener(t, addr)
+
+	if s.Draining() {
+		t.Fatal("Draining() reported true before Shutdown was called")
+	}
+
+	shutdownDone := make(chan error, 1)
+	go func() { shutdownDone <- s.Shutdown() }()
+
+	time.Sleep(10 * time.Millisecond)
+	if !s.Draining() {
+		t.Fatal("Draining() reported false during the ShutdownDelay window")
+	}
+	if ready.Load() {
+		t.Fatal("readiness flag was not cleared once draining started")
+	}
+
+	if err := <-shutdownDone; err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}
+
+func TestRunMultiListenerShutdown(t *testing.T) {
+	mainPort := freePort(t)
+	redirectPort := freePort(t)
+	introspectionPort := freePort(t)
+
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(mainPort),
+		WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		WithRedirectHTTP(redirectPort),
+		WithIntrospection(fmt.Sprintf("127.0.0.1:%d", introspectionPort)),
+		WithShutdownTimeout(2*time.Second),
+	)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+
+	waitForListener(t, fmt.Sprintf("127.0.0.1:%d", mainPort))
+	waitForListener(t, fmt.Sprintf("127.0.0.1:%d", redirectPort))
+	waitForListener(t, fmt.Sprintf("127.0.0.1:%d", introspectionPort))
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", introspectionPort))
+	if err != nil {
+		t.Fatalf("introspection listener not reachable: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected /healthz 200, got %d", resp.StatusCode)
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Run did not return once every listener was shut down")
+	}
+}
+
+func TestActiveConnsDeregisterHijackedConn(t *testing.T) {
+	port := freePort(t)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	hijacked := make(chan net.Conn, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("ResponseWriter does not support hijacking")
+			return
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		hijacked <- conn
+	})
+
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(port),
+		WithHandler(handler),
+		WithShutdownTimeout(2*time.Second),
+	)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+	waitForListener(t, addr)
+
+	go func() {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+		}
+	}()
+
+	var conn net.Conn
+	select {
+	case conn = <-hijacked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler never hijacked a connection")
+	}
+	defer conn.Close()
+
+	if n := s.ActiveConns(); n != 1 {
+		t.Fatalf("ActiveConns() = %d, want 1 after hijack", n)
+	}
+
+	s.DeregisterConn(conn)
+	if n := s.ActiveConns(); n != 0 {
+		t.Fatalf("ActiveConns() = %d after DeregisterConn, want 0 (hijacked conns leak without it)", n)
+	}
+
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}
+
+func TestForceCloseClosesHijackedConnWithNothingElseInFlight(t *testing.T) {
+	port := freePort(t)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	// http.Server.Shutdown stops tracking a connection the instant it's
+	// hijacked, so with nothing else in flight it reports quiescent success
+	// almost immediately - it never times out, so forceClose must trigger
+	// off of a post-shutdown ActiveConns check rather than solely off of
+	// g.Wait()'s error, or this hijacked conn would never get closed.
+	hijacked := make(chan net.Conn, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Error("ResponseWriter does not support hijacking")
+			return
+		}
+		conn, _, err := hj.Hijack()
+		if err != nil {
+			t.Errorf("hijack failed: %v", err)
+			return
+		}
+		hijacked <- conn
+	})
+
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(port),
+		WithHandler(handler),
+		WithShutdownTimeout(2*time.Second),
+	)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+	waitForListener(t, addr)
+
+	go func() {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+		}
+	}()
+
+	select {
+	case <-hijacked:
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler never hijacked a connection")
+	}
+
+	if n := s.ActiveConns(); n != 1 {
+		t.Fatalf("ActiveConns() = %d, want 1", n)
+	}
+
+	// Nobody calls DeregisterConn here: Shutdown's graceful pass will return
+	// successfully almost instantly since the hijacked conn isn't tracked by
+	// srv.Shutdown at all, so the only thing that can reap it is the
+	// post-shutdown ActiveConns fallback.
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+
+	if n := s.ActiveConns(); n != 0 {
+		t.Fatalf("ActiveConns() = %d after Shutdown, want 0 (hijacked conn was never force-closed)", n)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}
+
+// TestListenAndServeWithShutdownAlreadyDoneContext guards against a
+// regression where Shutdown, triggered before Run's goroutine finished
+// registering s.srv/s.redirectSrv/s.introspectionSrv, would see them all nil
+// and return immediately while Run went on to block forever in
+// ListenAndServe. Run with -race to also catch the unsynchronized access.
+func TestListenAndServeWithShutdownAlreadyDoneContext(t *testing.T) {
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(freePort(t)),
+		WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		WithShutdownTimeout(2*time.Second),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() // already done before ListenAndServeWithShutdown's goroutine even starts
+
+	done := make(chan error, 1)
+	go func() {
+		done <- s.ListenAndServeWithShutdown(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ListenAndServeWithShutdown returned error: %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("ListenAndServeWithShutdown deadlocked on an already-cancelled context")
+	}
+}
+
+func TestShutdownHooksOrderAndAbort(t *testing.T) {
+	port := freePort(t)
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+
+	var order []string
+	s := NewServer(
+		WithAddr("127.0.0.1"),
+		WithPort(port),
+		WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+		WithBeforeShutdown(func() bool {
+			order = append(order, "before1")
+			return true
+		}),
+		WithBeforeShutdown(func() bool {
+			order = append(order, "before2-veto")
+			return false
+		}),
+		WithAfterShutdown(func(err error) {
+			order = append(order, fmt.Sprintf("after1:%v", err != nil))
+		}),
+		WithAfterShutdown(func(err error) {
+			order = append(order, fmt.Sprintf("after2:%v", err != nil))
+		}),
+	)
+
+	runErr := make(chan error, 1)
+	go func() { runErr <- s.Run() }()
+	waitForListener(t, addr)
+
+	if err := s.Shutdown(); !errors.Is(err, errShutdownAborted) {
+		t.Fatalf("Shutdown() = %v, want errShutdownAborted", err)
+	}
+
+	want := []string{"before1", "before2-veto", "after1:true", "after2:true"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("hook order = %v, want %v", order, want)
+	}
+
+	// The veto must have stopped shutdown before the listener closed.
+	probe, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("listener should still be running after a vetoed shutdown: %v", err)
+	}
+	probe.Close()
+
+	s.beforeShutdownHooks = nil
+	if err := s.Shutdown(); err != nil {
+		t.Fatalf("Shutdown returned error: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+}